@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sessionCookieName = "lodestone_session"
+
+// refreshSkew is how far ahead of accessJwt's expiry we proactively refresh
+// the session, so a request doesn't race a token expiring mid-flight.
+const refreshSkew = 60 * time.Second
+
+// Session holds server-side state for one logged-in atproto account: its
+// session tokens and the PDS they were issued against. A *Session is shared
+// across every concurrent request for that cookie, and refreshSession
+// mutates AccessJWT, RefreshJWT, and AccessExp in place, so reads and
+// writes of those fields go through mu rather than touching them directly.
+type Session struct {
+	DID         string
+	PDSEndpoint string
+
+	mu         sync.Mutex
+	AccessJWT  string
+	RefreshJWT string
+	AccessExp  time.Time
+}
+
+// AccessToken returns the session's current access token.
+func (s *Session) AccessToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.AccessJWT
+}
+
+// NeedsRefresh reports whether the access token is within refreshSkew of
+// expiring.
+func (s *Session) NeedsRefresh() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Until(s.AccessExp) < refreshSkew
+}
+
+// SessionStore holds Sessions keyed by an opaque, server-generated token.
+// It's separate from ResolutionCache: sessions carry mutable, sensitive
+// state rather than TTL'd, re-derivable resolution results.
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewSessionStore returns an empty SessionStore.
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: make(map[string]*Session)}
+}
+
+// Put stores session under a new random ID and returns that ID.
+func (s *SessionStore) Put(session *Session) (string, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.sessions[id] = session
+	s.mu.Unlock()
+	return id, nil
+}
+
+// Get returns the session stored under id, if any.
+func (s *SessionStore) Get(id string) (*Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	return session, ok
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// sessionStore is the process-wide session store.
+var sessionStore = NewSessionStore()
+
+type loginRequest struct {
+	Identifier string `json:"identifier"`
+	Password   string `json:"password"`
+	// PDSEndpoint lets callers skip handle/DID resolution when they already
+	// know which PDS the account lives on.
+	PDSEndpoint string `json:"pdsEndpoint"`
+}
+
+type createSessionResponse struct {
+	Did        string `json:"did"`
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+}
+
+// handleLogin wraps com.atproto.server.createSession: it resolves the
+// account's PDS (unless one is given explicitly), authenticates with the
+// app password, and stores the resulting session server-side behind a
+// cookie so later requests can be authenticated without re-sending
+// credentials.
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Identifier == "" || req.Password == "" {
+		http.Error(w, "identifier and password are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	pdsEndpoint := req.PDSEndpoint
+	if pdsEndpoint == "" {
+		_, resolved, _, err := resolvePDS(ctx, req.Identifier)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to resolve PDS for %s: %v", req.Identifier, err), http.StatusBadRequest)
+			return
+		}
+		pdsEndpoint = resolved
+	}
+
+	reqBody, err := json.Marshal(map[string]string{"identifier": req.Identifier, "password": req.Password})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respBody, err := postJSON(ctx, fmt.Sprintf("%s/xrpc/com.atproto.server.createSession", strings.TrimSuffix(pdsEndpoint, "/")), reqBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("login failed: %v", err), http.StatusUnauthorized)
+		return
+	}
+
+	var sessionResp createSessionResponse
+	if err := json.Unmarshal(respBody, &sessionResp); err != nil {
+		http.Error(w, fmt.Sprintf("unexpected createSession response: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	accessExp, _ := jwtExpiry(sessionResp.AccessJwt)
+	session := &Session{
+		DID:         sessionResp.Did,
+		PDSEndpoint: pdsEndpoint,
+		AccessJWT:   sessionResp.AccessJwt,
+		RefreshJWT:  sessionResp.RefreshJwt,
+		AccessExp:   accessExp,
+	}
+
+	sessionID, err := sessionStore.Put(session)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"did": session.DID})
+}
+
+// sessionFromRequest returns the Session for r's session cookie, refreshing
+// it first if the access token is within refreshSkew of expiring.
+func sessionFromRequest(r *http.Request) (*Session, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, false
+	}
+
+	session, ok := sessionStore.Get(cookie.Value)
+	if !ok {
+		return nil, false
+	}
+
+	if session.NeedsRefresh() {
+		// Best effort: if the refresh fails we still return the session,
+		// since the existing accessJwt may have life left in it yet.
+		refreshSession(r.Context(), session)
+	}
+
+	return session, true
+}
+
+type refreshSessionResponse struct {
+	Did        string `json:"did"`
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+}
+
+// refreshSession wraps com.atproto.server.refreshSession, updating session
+// in place with the new token pair.
+func refreshSession(ctx context.Context, session *Session) error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if time.Until(session.AccessExp) >= refreshSkew {
+		// Another goroutine already refreshed this session while we were
+		// waiting for the lock.
+		return nil
+	}
+
+	url := fmt.Sprintf("%s/xrpc/com.atproto.server.refreshSession", strings.TrimSuffix(session.PDSEndpoint, "/"))
+	body, err := sharedHTTPClient.PostAuthorized(ctx, url, nil, session.RefreshJWT)
+	if err != nil {
+		return err
+	}
+
+	var refreshResp refreshSessionResponse
+	if err := json.Unmarshal(body, &refreshResp); err != nil {
+		return err
+	}
+
+	session.AccessJWT = refreshResp.AccessJwt
+	session.RefreshJWT = refreshResp.RefreshJwt
+	session.AccessExp, _ = jwtExpiry(refreshResp.AccessJwt)
+	return nil
+}
+
+// jwtExpiry reads the exp claim out of a JWT's payload without verifying
+// its signature; we trust it because it just came from the PDS over TLS.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("malformed JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// postJSON POSTs body to url via the shared HTTP client, so login gets the
+// same circuit breaking as every other upstream call.
+func postJSON(ctx context.Context, url string, body []byte) ([]byte, error) {
+	return sharedHTTPClient.PostAuthorized(ctx, url, body, "")
+}
+
+// handleXRPCPassthrough forwards any XRPC method to the caller's own PDS,
+// authenticated with their session, so the proxy isn't limited to the
+// hard-coded public repo methods (e.g. app.bsky.feed.getTimeline).
+func handleXRPCPassthrough(w http.ResponseWriter, r *http.Request) {
+	nsid := strings.TrimPrefix(r.URL.Path, "/xrpc/")
+	if nsid == "" {
+		http.Error(w, "missing NSID", http.StatusBadRequest)
+		return
+	}
+
+	session, ok := sessionFromRequest(r)
+	if !ok {
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	target := fmt.Sprintf("%s/xrpc/%s", strings.TrimSuffix(session.PDSEndpoint, "/"), nsid)
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target, r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+session.AccessToken())
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+
+	status, body, err := sharedHTTPClient.DoPassthrough(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("upstream request failed: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
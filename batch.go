@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// maxBatchURIs caps how many URIs a single /resolve/batch request may
+// resolve, so one caller can't monopolize the worker pool.
+const maxBatchURIs = 100
+
+// maxBatchWorkers bounds how many URIs in a batch are resolved concurrently.
+const maxBatchWorkers = 16
+
+type batchRequest struct {
+	URIs []string `json:"uris"`
+}
+
+// batchResultItem is the per-URI outcome returned by /resolve/batch.
+type batchResultItem struct {
+	URI      string          `json:"uri"`
+	Status   int             `json:"status"`
+	Attempts int             `json:"attempts,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	CacheHit bool            `json:"cacheHit"`
+}
+
+// handleResolveBatch resolves a batch of AT-URIs concurrently, sharing the
+// cache/singleflight layer with handleResolve. One bad URI doesn't fail the
+// rest of the batch.
+func handleResolveBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(req.URIs) == 0 {
+		http.Error(w, "uris must not be empty", http.StatusBadRequest)
+		return
+	}
+	if len(req.URIs) > maxBatchURIs {
+		http.Error(w, fmt.Sprintf("too many uris: max %d per batch", maxBatchURIs), http.StatusBadRequest)
+		return
+	}
+
+	var bearerToken string
+	if session, ok := sessionFromRequest(r); ok {
+		bearerToken = session.AccessToken()
+	}
+
+	results := make([]batchResultItem, len(req.URIs))
+	sem := make(chan struct{}, maxBatchWorkers)
+	var wg sync.WaitGroup
+
+	for i, uri := range req.URIs {
+		wg.Add(1)
+		go func(i int, uri string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			item := batchResultItem{URI: uri}
+			result := resolveATURI(r.Context(), uri, bearerToken)
+			item.CacheHit = result.CacheHit
+			if result.Err != nil {
+				item.Error = result.Err.Error()
+				var upstreamErr *UpstreamError
+				if errors.As(result.Err, &upstreamErr) && upstreamErr.Status != 0 {
+					item.Status = upstreamErr.Status
+				} else {
+					item.Status = http.StatusInternalServerError
+				}
+				item.Attempts = upstreamErrAttempts(result.Err)
+			} else {
+				item.Status = http.StatusOK
+				item.Body = json.RawMessage(result.Body)
+			}
+			results[i] = item
+		}(i, uri)
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// upstreamErrAttempts returns the attempt count carried by err if it's (or
+// wraps) an *UpstreamError, or 0 otherwise.
+func upstreamErrAttempts(err error) int {
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.Attempts
+	}
+	return 0
+}
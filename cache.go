@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CacheBackend is the pluggable storage behind ResolutionCache. Keys and
+// values are opaque strings so MemoryCache and RedisCache can share one
+// interface regardless of how they actually store data.
+type CacheBackend interface {
+	Get(key string) (value string, found bool)
+	Set(key, value string, ttl time.Duration)
+}
+
+// negativeCacheValue marks a cached failure (e.g. NXDOMAIN-like lookup
+// misses) so repeated requests for a known-bad handle or DID fail fast
+// instead of re-hitting the upstream every time.
+const negativeCacheValue = "\x00negative"
+
+// CacheConfig configures the cache backend and per-entry-kind TTLs.
+type CacheConfig struct {
+	Backend     string        `yaml:"backend"` // "memory" (default) or "redis"
+	RedisAddr   string        `yaml:"redis_addr"`
+	MaxEntries  int           `yaml:"max_entries"`
+	HandleTTL   time.Duration `yaml:"handle_ttl"`
+	DIDTTL      time.Duration `yaml:"did_ttl"`
+	PDSTTL      time.Duration `yaml:"pds_ttl"`
+	NegativeTTL time.Duration `yaml:"negative_ttl"`
+}
+
+func (c CacheConfig) withDefaults() CacheConfig {
+	if c.MaxEntries == 0 {
+		c.MaxEntries = 10000
+	}
+	if c.HandleTTL == 0 {
+		c.HandleTTL = 5 * time.Minute
+	}
+	if c.DIDTTL == 0 {
+		c.DIDTTL = 1 * time.Hour
+	}
+	if c.PDSTTL == 0 {
+		c.PDSTTL = 1 * time.Hour
+	}
+	if c.NegativeTTL == 0 {
+		c.NegativeTTL = 30 * time.Second
+	}
+	return c
+}
+
+// ResolutionCache sits in front of handle, DID, and PDS-endpoint
+// resolution, adding TTL caching, negative caching, and singleflight
+// deduplication on top of whichever CacheBackend is configured.
+type ResolutionCache struct {
+	backend CacheBackend
+	cfg     CacheConfig
+	group   singleflightGroup
+}
+
+// NewResolutionCache builds a ResolutionCache over backend.
+func NewResolutionCache(backend CacheBackend, cfg CacheConfig) *ResolutionCache {
+	return &ResolutionCache{backend: backend, cfg: cfg.withDefaults()}
+}
+
+// ResolveHandle returns the DID for handle, calling resolve on a cache miss
+// and deduplicating concurrent misses for the same handle. The bool result
+// reports whether the value came from the cache.
+func (c *ResolutionCache) ResolveHandle(ctx context.Context, handle string, resolve func(context.Context, string) (string, error)) (string, bool, error) {
+	key := "handle:" + handle
+	if v, ok := c.backend.Get(key); ok {
+		cacheHandleMetric.hit()
+		if v == negativeCacheValue {
+			return "", true, fmt.Errorf("handle resolution previously failed: %s", handle)
+		}
+		return v, true, nil
+	}
+	cacheHandleMetric.miss()
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		did, err := resolve(ctx, handle)
+		if err != nil {
+			c.backend.Set(key, negativeCacheValue, c.cfg.NegativeTTL)
+			return "", err
+		}
+		c.backend.Set(key, did, c.cfg.HandleTTL)
+		return did, nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return v.(string), false, nil
+}
+
+// ResolveDID returns the DID document for did, calling resolve on a cache
+// miss and deduplicating concurrent misses for the same DID. The bool
+// result reports whether the value came from the cache.
+func (c *ResolutionCache) ResolveDID(ctx context.Context, did string, resolve func(context.Context, string) (*DIDDocument, error)) (*DIDDocument, bool, error) {
+	key := "did:" + did
+	if v, ok := c.backend.Get(key); ok {
+		cacheDIDMetric.hit()
+		if v == negativeCacheValue {
+			return nil, true, fmt.Errorf("DID resolution previously failed: %s", did)
+		}
+		var doc DIDDocument
+		if err := json.Unmarshal([]byte(v), &doc); err == nil {
+			return &doc, true, nil
+		}
+	} else {
+		cacheDIDMetric.miss()
+	}
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		doc, err := resolve(ctx, did)
+		if err != nil {
+			c.backend.Set(key, negativeCacheValue, c.cfg.NegativeTTL)
+			return nil, err
+		}
+		if data, err := json.Marshal(doc); err == nil {
+			c.backend.Set(key, string(data), c.cfg.DIDTTL)
+		}
+		return doc, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return v.(*DIDDocument), false, nil
+}
+
+// PDSEndpoint returns the cached PDS service endpoint for did, deriving and
+// caching it from doc on a miss. The bool result reports whether the value
+// came from the cache.
+func (c *ResolutionCache) PDSEndpoint(did string, doc *DIDDocument) (string, bool) {
+	key := "pds:" + did
+	if v, ok := c.backend.Get(key); ok {
+		cachePDSMetric.hit()
+		return v, true
+	}
+	cachePDSMetric.miss()
+
+	endpoint := extractPDSEndpoint(doc)
+	if endpoint != "" {
+		c.backend.Set(key, endpoint, c.cfg.PDSTTL)
+	}
+	return endpoint, false
+}
+
+// NewCacheBackend builds the backend selected by cfg.Backend.
+func NewCacheBackend(cfg CacheConfig) (CacheBackend, error) {
+	cfg = cfg.withDefaults()
+	switch cfg.Backend {
+	case "", "memory":
+		return NewMemoryCache(cfg.MaxEntries), nil
+	case "redis":
+		return NewRedisCache(cfg.RedisAddr)
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %s", cfg.Backend)
+	}
+}
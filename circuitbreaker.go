@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreaker trips per host after a run of consecutive upstream
+// failures, so one dead PDS or directory doesn't tie up every worker
+// goroutine retrying it. Once open, it allows a single half-open probe
+// after a cooldown before fully closing or re-opening.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	hosts     map[string]*hostCircuit
+	threshold int
+	cooldown  time.Duration
+}
+
+type hostCircuit struct {
+	state           circuitState
+	consecutiveFail int
+	openedAt        time.Time
+}
+
+// NewCircuitBreaker returns a CircuitBreaker that opens after threshold
+// consecutive failures and probes again after cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return &CircuitBreaker{hosts: make(map[string]*hostCircuit), threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a request to host may proceed, transitioning an
+// open circuit to half-open once the cooldown has elapsed. Only the caller
+// that performs that transition gets true back; concurrent callers that
+// find the circuit already half-open are rejected until the in-flight
+// probe reports success or failure, so exactly one probe is in flight.
+func (b *CircuitBreaker) Allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hc := b.hostCircuit(host)
+	switch hc.state {
+	case circuitClosed:
+		return true
+	case circuitHalfOpen:
+		return false
+	default: // circuitOpen
+		if time.Since(hc.openedAt) >= b.cooldown {
+			hc.state = circuitHalfOpen
+			return true
+		}
+		return false
+	}
+}
+
+// RecordSuccess closes the circuit for host.
+func (b *CircuitBreaker) RecordSuccess(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hc := b.hostCircuit(host)
+	hc.state = circuitClosed
+	hc.consecutiveFail = 0
+}
+
+// RecordFailure counts a failure for host, opening the circuit once the
+// threshold is reached, or immediately if a half-open probe just failed.
+func (b *CircuitBreaker) RecordFailure(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hc := b.hostCircuit(host)
+	if hc.state == circuitHalfOpen {
+		hc.state = circuitOpen
+		hc.openedAt = time.Now()
+		return
+	}
+
+	hc.consecutiveFail++
+	if hc.consecutiveFail >= b.threshold {
+		hc.state = circuitOpen
+		hc.openedAt = time.Now()
+	}
+}
+
+func (b *CircuitBreaker) hostCircuit(host string) *hostCircuit {
+	hc, ok := b.hosts[host]
+	if !ok {
+		hc = &hostCircuit{}
+		b.hosts[host] = hc
+	}
+	return hc
+}
+
+func errCircuitOpen(host string) error {
+	return fmt.Errorf("circuit breaker open for host %s", host)
+}
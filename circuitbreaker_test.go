@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b := NewCircuitBreaker(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if !b.Allow("host") {
+			t.Fatalf("expected closed circuit to allow request %d", i)
+		}
+		b.RecordFailure("host")
+	}
+
+	if b.Allow("host") {
+		t.Fatal("expected circuit to be open after threshold consecutive failures")
+	}
+}
+
+func TestCircuitBreakerRecordSuccessResetsFailureCount(t *testing.T) {
+	b := NewCircuitBreaker(3, time.Minute)
+
+	b.RecordFailure("host")
+	b.RecordFailure("host")
+	b.RecordSuccess("host")
+	b.RecordFailure("host")
+	b.RecordFailure("host")
+
+	if !b.Allow("host") {
+		t.Fatal("expected circuit to remain closed: success should reset the failure streak")
+	}
+}
+
+func TestCircuitBreakerHalfOpenAllowsOnlyOneProbe(t *testing.T) {
+	b := NewCircuitBreaker(1, 10*time.Millisecond)
+
+	b.RecordFailure("host") // opens immediately (threshold 1)
+	if b.Allow("host") {
+		t.Fatal("expected circuit to be open before cooldown elapses")
+	}
+
+	time.Sleep(15 * time.Millisecond)
+
+	if !b.Allow("host") {
+		t.Fatal("expected the first caller after cooldown to get the half-open probe")
+	}
+	if b.Allow("host") {
+		t.Fatal("expected concurrent callers to be rejected while a half-open probe is in flight")
+	}
+
+	b.RecordSuccess("host")
+	if !b.Allow("host") {
+		t.Fatal("expected circuit to close after a successful probe")
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	b := NewCircuitBreaker(1, 10*time.Millisecond)
+
+	b.RecordFailure("host")
+	time.Sleep(15 * time.Millisecond)
+	if !b.Allow("host") {
+		t.Fatal("expected probe to be allowed after cooldown")
+	}
+
+	b.RecordFailure("host")
+	if b.Allow("host") {
+		t.Fatal("expected circuit to re-open immediately after a failed probe")
+	}
+}
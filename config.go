@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is Lodestone's boot-time configuration, loaded from config.yml.
+type Config struct {
+	Methods map[string]MethodConfig `yaml:"methods"`
+	Cache   CacheConfig             `yaml:"cache"`
+	DNS     DNSConfig               `yaml:"dns"`
+	HTTP    HTTPClientConfig        `yaml:"http"`
+}
+
+// DNSConfig configures handle resolution's DNS TXT lookup.
+type DNSConfig struct {
+	// Server, if set, is used instead of the system resolver (host:port,
+	// e.g. "1.1.1.1:53"), for split-horizon DNS or testing against a local
+	// unbound.
+	Server  string        `yaml:"server"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// MethodConfig configures a single DID method's resolver: where to send
+// requests, optional mirrors to fall back to, and how long to wait.
+type MethodConfig struct {
+	Endpoint string        `yaml:"endpoint"`
+	Mirrors  []string      `yaml:"mirrors"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// MethodConfig returns the configuration for method, or a zero-value
+// MethodConfig if none was supplied (built-in resolvers fall back to their
+// own defaults in that case).
+func (c *Config) MethodConfig(method string) MethodConfig {
+	if c == nil {
+		return MethodConfig{}
+	}
+	return c.Methods[method]
+}
+
+// LoadConfig reads and parses the YAML config file at path. A missing file
+// is not an error: callers get a zero-value Config and built-in resolvers
+// use their defaults.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
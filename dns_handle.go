@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// handleResolverConfig is set at boot from the loaded Config and read by
+// resolveHandle.
+var handleResolverConfig = DNSConfig{Timeout: 3 * time.Second}
+
+const dnsHeadStart = 50 * time.Millisecond
+
+// resolveHandle resolves an atproto handle to a DID per the atproto
+// handle-resolution spec: a DNS TXT lookup at _atproto.<handle> and the
+// https://<handle>/.well-known/atproto-did fallback race, with DNS given a
+// small head start since it's almost always faster when present.
+func resolveHandle(ctx context.Context, handle string) (string, error) {
+	timeout := handleResolverConfig.Timeout
+	if timeout == 0 {
+		timeout = 3 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return resolveHandleRace(ctx, handle,
+		func(ctx context.Context, handle string) (string, error) {
+			return resolveHandleDNS(ctx, handle, handleResolverConfig.Server)
+		},
+		resolveHandleHTTPS,
+	)
+}
+
+type handleLookupResult struct {
+	did string
+	err error
+}
+
+// handleLookupFunc looks up handle's DID via one resolution method (DNS TXT
+// or HTTPS well-known).
+type handleLookupFunc func(ctx context.Context, handle string) (string, error)
+
+// resolveHandleRace runs lookupDNS and lookupHTTPS concurrently (HTTPS
+// delayed by dnsHeadStart), returning whichever succeeds first. If both
+// fail, both errors are reported. The lookups are passed in rather than
+// called directly so tests can exercise the race's selection logic without
+// touching a real resolver or network.
+func resolveHandleRace(ctx context.Context, handle string, lookupDNS, lookupHTTPS handleLookupFunc) (string, error) {
+	dnsCh := make(chan handleLookupResult, 1)
+	httpsCh := make(chan handleLookupResult, 1)
+
+	go func() {
+		did, err := lookupDNS(ctx, handle)
+		dnsCh <- handleLookupResult{did, err}
+	}()
+	go func() {
+		select {
+		case <-time.After(dnsHeadStart):
+		case <-ctx.Done():
+			httpsCh <- handleLookupResult{"", ctx.Err()}
+			return
+		}
+		did, err := lookupHTTPS(ctx, handle)
+		httpsCh <- handleLookupResult{did, err}
+	}()
+
+	var dnsDone, httpsDone bool
+	var dnsRes, httpsRes handleLookupResult
+	for {
+		select {
+		case dnsRes = <-dnsCh:
+			dnsDone = true
+			if dnsRes.err == nil {
+				return dnsRes.did, nil
+			}
+		case httpsRes = <-httpsCh:
+			httpsDone = true
+			if httpsRes.err == nil {
+				return httpsRes.did, nil
+			}
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if dnsDone && httpsDone {
+			return "", fmt.Errorf("could not resolve handle %s (dns: %v, https: %v)", handle, dnsRes.err, httpsRes.err)
+		}
+	}
+}
+
+// resolveHandleDNS looks up the `did=did:...` TXT record at _atproto.<handle>.
+func resolveHandleDNS(ctx context.Context, handle, dnsServer string) (string, error) {
+	records, err := dnsResolverFor(dnsServer).LookupTXT(ctx, "_atproto."+handle)
+	if err != nil {
+		return "", err
+	}
+	for _, rec := range records {
+		if did, ok := strings.CutPrefix(rec, "did="); ok {
+			return did, nil
+		}
+	}
+	return "", fmt.Errorf("no did= TXT record at _atproto.%s", handle)
+}
+
+// dnsResolverFor returns a resolver that queries dnsServer (host:port)
+// directly, or the system default resolver when dnsServer is empty.
+func dnsResolverFor(dnsServer string) *net.Resolver {
+	if dnsServer == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, dnsServer)
+		},
+	}
+}
+
+// resolveHandleHTTPS fetches https://<handle>/.well-known/atproto-did via
+// sharedHTTPClient, so a flaky or dead well-known host gets the same
+// retry/circuit-breaking treatment as every other upstream call instead of
+// being hit fresh on every handle-resolution race.
+func resolveHandleHTTPS(ctx context.Context, handle string) (string, error) {
+	body, err := sharedHTTPClient.Get(ctx, fmt.Sprintf("https://%s/.well-known/atproto-did", handle))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func delayedLookup(delay time.Duration, did string, err error) handleLookupFunc {
+	return func(ctx context.Context, handle string) (string, error) {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		return did, err
+	}
+}
+
+func TestResolveHandleRaceDNSWins(t *testing.T) {
+	ctx := context.Background()
+	did, err := resolveHandleRace(ctx, "example.com",
+		delayedLookup(0, "did:plc:dns", nil),
+		delayedLookup(50*time.Millisecond, "did:plc:https", nil),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if did != "did:plc:dns" {
+		t.Fatalf("got %q, want the DNS result", did)
+	}
+}
+
+func TestResolveHandleRaceHTTPSWinsWhenDNSFails(t *testing.T) {
+	ctx := context.Background()
+	did, err := resolveHandleRace(ctx, "example.com",
+		delayedLookup(0, "", fmt.Errorf("no TXT record")),
+		delayedLookup(0, "did:plc:https", nil),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if did != "did:plc:https" {
+		t.Fatalf("got %q, want the HTTPS result", did)
+	}
+}
+
+func TestResolveHandleRaceHTTPSWinsWhenSlowerButDNSFails(t *testing.T) {
+	ctx := context.Background()
+	did, err := resolveHandleRace(ctx, "example.com",
+		delayedLookup(5*time.Millisecond, "", fmt.Errorf("no TXT record")),
+		delayedLookup(20*time.Millisecond, "did:plc:https", nil),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if did != "did:plc:https" {
+		t.Fatalf("got %q, want the HTTPS result", did)
+	}
+}
+
+func TestResolveHandleRaceBothFail(t *testing.T) {
+	ctx := context.Background()
+	dnsErr := fmt.Errorf("no TXT record")
+	httpsErr := fmt.Errorf("well-known endpoint returned status 404")
+
+	_, err := resolveHandleRace(ctx, "example.com",
+		delayedLookup(0, "", dnsErr),
+		delayedLookup(0, "", httpsErr),
+	)
+	if err == nil {
+		t.Fatal("expected an error when both lookups fail")
+	}
+}
+
+func TestResolveHandleRaceContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := resolveHandleRace(ctx, "example.com",
+		delayedLookup(time.Second, "did:plc:dns", nil),
+		delayedLookup(time.Second, "did:plc:https", nil),
+	)
+	if err == nil {
+		t.Fatal("expected an error for an already-canceled context")
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// HTTPClientConfig tunes retry and circuit-breaking behavior shared by all
+// upstream HTTP calls (DID resolution and XRPC).
+type HTTPClientConfig struct {
+	MaxAttempts      int           `yaml:"max_attempts"`
+	BaseBackoff      time.Duration `yaml:"base_backoff"`
+	MaxBackoff       time.Duration `yaml:"max_backoff"`
+	CircuitThreshold int           `yaml:"circuit_threshold"`
+	CircuitCooldown  time.Duration `yaml:"circuit_cooldown"`
+	// RequestTimeout bounds a single attempt, independent of whatever
+	// deadline (if any) the caller's context already carries. Without it, a
+	// PDS that accepts the connection and then never responds hangs the
+	// goroutine forever without ever erroring, so the circuit breaker never
+	// sees a failure to trip on.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+}
+
+func (c HTTPClientConfig) withDefaults() HTTPClientConfig {
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = 4
+	}
+	if c.BaseBackoff == 0 {
+		c.BaseBackoff = 200 * time.Millisecond
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = 5 * time.Second
+	}
+	if c.CircuitThreshold == 0 {
+		c.CircuitThreshold = 5
+	}
+	if c.CircuitCooldown == 0 {
+		c.CircuitCooldown = 30 * time.Second
+	}
+	if c.RequestTimeout == 0 {
+		c.RequestTimeout = 10 * time.Second
+	}
+	return c
+}
+
+// HTTPClient wraps http.Client with a per-request deadline inherited from
+// the caller's context, retry-with-backoff on transient upstream errors,
+// and a per-host circuit breaker. All of Lodestone's upstream GETs
+// (DID resolution, describeRepo, listRecords, getRecord) go through it.
+type HTTPClient struct {
+	client  *http.Client
+	cfg     HTTPClientConfig
+	breaker *CircuitBreaker
+}
+
+// NewHTTPClient builds an HTTPClient from cfg.
+func NewHTTPClient(cfg HTTPClientConfig) *HTTPClient {
+	cfg = cfg.withDefaults()
+	return &HTTPClient{
+		client:  &http.Client{},
+		cfg:     cfg,
+		breaker: NewCircuitBreaker(cfg.CircuitThreshold, cfg.CircuitCooldown),
+	}
+}
+
+// UpstreamError reports the final outcome of a failed Get: the last
+// upstream status code seen (0 if none was received) and how many
+// attempts were made, so operators can tell a dead PDS from a bad DID.
+type UpstreamError struct {
+	URL      string
+	Status   int
+	Attempts int
+	Err      error
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("%s: upstream status %d after %d attempt(s): %v", e.URL, e.Status, e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("%s: failed after %d attempt(s): %v", e.URL, e.Attempts, e.Err)
+}
+
+func (e *UpstreamError) Unwrap() error { return e.Err }
+
+// Get performs an idempotent GET against rawURL. It retries on 5xx, 429,
+// and connection errors using truncated exponential backoff with full
+// jitter, honors Retry-After when present, and trips rawURL's host's
+// circuit breaker after repeated failures.
+func (c *HTTPClient) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	return c.GetAuthorized(ctx, rawURL, "")
+}
+
+// GetAuthorized is Get with an optional bearer token attached, so
+// authenticated proxy mode can forward a session's accessJwt to the PDS.
+func (c *HTTPClient) GetAuthorized(ctx context.Context, rawURL, bearerToken string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+
+	if !c.breaker.Allow(host) {
+		return nil, &UpstreamError{URL: rawURL, Err: errCircuitOpen(host)}
+	}
+
+	var lastErr error
+	var lastStatus int
+	attempts := 0
+	for attempt := 1; attempt <= c.cfg.MaxAttempts; attempt++ {
+		attempts = attempt
+		attemptCtx, cancel := context.WithTimeout(ctx, c.cfg.RequestTimeout)
+		req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		if bearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+bearerToken)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			cancel()
+			lastErr = err
+			c.breaker.RecordFailure(host)
+			if !c.sleepBeforeRetry(ctx, attempt, 0) {
+				break
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			lastStatus = resp.StatusCode
+			lastErr = fmt.Errorf("upstream status %d", resp.StatusCode)
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+			cancel()
+			c.breaker.RecordFailure(host)
+			if !c.sleepBeforeRetry(ctx, attempt, retryAfter) {
+				break
+			}
+			continue
+		}
+
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		cancel()
+		c.breaker.RecordSuccess(host)
+		if readErr != nil {
+			return nil, readErr
+		}
+		if resp.StatusCode != http.StatusOK {
+			return body, &UpstreamError{URL: rawURL, Status: resp.StatusCode, Attempts: attempt, Err: fmt.Errorf("non-200 response")}
+		}
+		return body, nil
+	}
+
+	return nil, &UpstreamError{URL: rawURL, Status: lastStatus, Attempts: attempts, Err: lastErr}
+}
+
+// PostAuthorized POSTs body to rawURL with an optional bearer token,
+// gated by the same per-host circuit breaker as Get/GetAuthorized. Unlike
+// those, it never retries: the session endpoints it's used for
+// (createSession, refreshSession) aren't idempotent, so a partial failure
+// shouldn't be replayed automatically.
+func (c *HTTPClient) PostAuthorized(ctx context.Context, rawURL string, body []byte, bearerToken string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+
+	if !c.breaker.Allow(host) {
+		return nil, &UpstreamError{URL: rawURL, Err: errCircuitOpen(host)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.breaker.RecordFailure(host)
+		return nil, &UpstreamError{URL: rawURL, Attempts: 1, Err: err}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.breaker.RecordFailure(host)
+		return nil, err
+	}
+
+	if resp.StatusCode >= 500 {
+		c.breaker.RecordFailure(host)
+	} else {
+		c.breaker.RecordSuccess(host)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return respBody, &UpstreamError{URL: rawURL, Status: resp.StatusCode, Attempts: 1, Err: fmt.Errorf("non-200 response: %s", respBody)}
+	}
+	return respBody, nil
+}
+
+// DoPassthrough forwards an already-built request through the shared
+// circuit breaker, for callers like the XRPC passthrough proxy that need to
+// preserve the upstream's exact status code and body rather than having
+// HTTPClient interpret them. It makes a single attempt: passthrough
+// requests may carry a non-idempotent method and an unbuffered body, so
+// retrying isn't safe in general. The request's deadline is bounded by
+// RequestTimeout for the same reason every other upstream call is: without
+// it, a PDS that accepts the connection and never responds would hang the
+// request goroutine forever instead of tripping the circuit breaker.
+func (c *HTTPClient) DoPassthrough(req *http.Request) (status int, body []byte, err error) {
+	host := req.URL.Host
+	if !c.breaker.Allow(host) {
+		return 0, nil, &UpstreamError{URL: req.URL.String(), Err: errCircuitOpen(host)}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), c.cfg.RequestTimeout)
+	defer cancel()
+	req = req.Clone(ctx)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.breaker.RecordFailure(host)
+		return 0, nil, &UpstreamError{URL: req.URL.String(), Attempts: 1, Err: err}
+	}
+	defer resp.Body.Close()
+
+	respBody, readErr := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 500 {
+		c.breaker.RecordFailure(host)
+	} else {
+		c.breaker.RecordSuccess(host)
+	}
+	if readErr != nil {
+		return resp.StatusCode, nil, readErr
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// sleepBeforeRetry waits before the next attempt using truncated
+// exponential backoff with full jitter (honoring retryAfter if it's
+// larger), returning false if no attempts remain or ctx ends first.
+func (c *HTTPClient) sleepBeforeRetry(ctx context.Context, attempt int, retryAfter time.Duration) bool {
+	if attempt >= c.cfg.MaxAttempts {
+		return false
+	}
+
+	backoff := c.cfg.BaseBackoff * time.Duration(int64(1)<<uint(attempt-1))
+	if backoff > c.cfg.MaxBackoff {
+		backoff = c.cfg.MaxBackoff
+	}
+	wait := time.Duration(rand.Int63n(int64(backoff) + 1))
+	if retryAfter > wait {
+		wait = retryAfter
+	}
+
+	select {
+	case <-time.After(wait):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// parseRetryAfter parses a Retry-After header, either delta-seconds or an
+// HTTP-date, returning 0 if absent or unparseable.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// sharedHTTPClient is set at boot from the loaded Config and used by every
+// upstream GET.
+var sharedHTTPClient = NewHTTPClient(HTTPClientConfig{})
@@ -1,17 +1,24 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
 )
 
 type DIDDocument struct {
-	ID      string    `json:"id"`
-	Service []Service `json:"service"`
+	ID                 string               `json:"id"`
+	VerificationMethod []VerificationMethod `json:"verificationMethod,omitempty"`
+	Service            []Service            `json:"service"`
+}
+
+type VerificationMethod struct {
+	ID                 string `json:"id"`
+	Type               string `json:"type"`
+	Controller         string `json:"controller"`
+	PublicKeyMultibase string `json:"publicKeyMultibase"`
 }
 
 type Service struct {
@@ -20,8 +27,36 @@ type Service struct {
 	ServiceEndpoint string `json:"serviceEndpoint"`
 }
 
+const defaultConfigPath = "config.yml"
+
+// resolutionCache is the shared caching layer in front of handle and DID
+// resolution. It's populated at boot from config and read by handleResolve.
+var resolutionCache *ResolutionCache
+
 func main() {
+	cfg, err := LoadConfig(defaultConfigPath)
+	if err != nil {
+		fmt.Printf("failed to load %s: %v\n", defaultConfigPath, err)
+		return
+	}
+	defaultRegistry = NewDefaultDIDRegistry(cfg)
+	if cfg.DNS.Timeout > 0 || cfg.DNS.Server != "" {
+		handleResolverConfig = cfg.DNS
+	}
+
+	backend, err := NewCacheBackend(cfg.Cache)
+	if err != nil {
+		fmt.Printf("failed to set up cache backend: %v\n", err)
+		return
+	}
+	resolutionCache = NewResolutionCache(backend, cfg.Cache)
+	sharedHTTPClient = NewHTTPClient(cfg.HTTP)
+
 	http.HandleFunc("/resolve", handleResolve)
+	http.HandleFunc("/resolve/batch", handleResolveBatch)
+	http.HandleFunc("/login", handleLogin)
+	http.HandleFunc("/xrpc/", handleXRPCPassthrough)
+	http.HandleFunc("/metrics", handleMetrics)
 	fmt.Println("Lodestone starting on :8080...")
 	http.ListenAndServe(":8080", nil)
 }
@@ -33,60 +68,95 @@ func handleResolve(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse AT-URI
-	authority, collection, rkey, err := parseATURI(atURI)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("invalid AT-URI: %v", err), http.StatusBadRequest)
-		return
+	var bearerToken string
+	if session, ok := sessionFromRequest(r); ok {
+		bearerToken = session.AccessToken()
 	}
 
-	// Resolve authority to DID if needed
-	did := authority
-	if !strings.HasPrefix(authority, "did:") {
-		// It's a handle, resolve to DID
-		resolvedDID, err := resolveHandle(authority)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("failed to resolve handle: %v", err), http.StatusInternalServerError)
-			return
-		}
-		did = resolvedDID
+	result := resolveATURI(r.Context(), atURI, bearerToken)
+	if result.Err != nil {
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	// Get DID document
-	didDoc, err := resolveDID(did)
+	// Return response as-is
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(result.Body)
+}
+
+// resolveResult is the outcome of resolving and dereferencing a single
+// AT-URI, shared by handleResolve and handleResolveBatch.
+type resolveResult struct {
+	Body     []byte
+	CacheHit bool
+	Err      error
+}
+
+// resolveATURI parses atURI, resolves its authority to a PDS, and makes the
+// appropriate XRPC call. CacheHit reports whether every resolution step
+// along the way (handle, DID, PDS endpoint) was served from cache.
+// bearerToken, if non-empty, is forwarded to the PDS as a Bearer
+// Authorization header, letting callers with a logged-in session
+// dereference records in non-public collections.
+func resolveATURI(ctx context.Context, atURI, bearerToken string) resolveResult {
+	authority, collection, rkey, err := parseATURI(atURI)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to resolve DID: %v", err), http.StatusInternalServerError)
-		return
+		return resolveResult{Err: fmt.Errorf("invalid AT-URI: %w", err)}
 	}
 
-	// Extract PDS endpoint
-	pdsEndpoint := extractPDSEndpoint(didDoc)
-	if pdsEndpoint == "" {
-		http.Error(w, "no PDS endpoint found in DID document", http.StatusInternalServerError)
-		return
+	did, pdsEndpoint, cacheHit, err := resolvePDS(ctx, authority)
+	if err != nil {
+		return resolveResult{Err: err}
 	}
 
 	// Make appropriate XRPC call based on URI components
-	var response []byte
+	var body []byte
 	if collection == "" {
 		// Just authority - describeRepo
-		response, err = describeRepo(pdsEndpoint, did)
+		body, err = describeRepo(ctx, pdsEndpoint, did, bearerToken)
 	} else if rkey == "" {
 		// Authority + collection - listRecords
-		response, err = listRecords(pdsEndpoint, did, collection)
+		body, err = listRecords(ctx, pdsEndpoint, did, collection, bearerToken)
 	} else {
 		// All three - getRecord
-		response, err = getRecord(pdsEndpoint, did, collection, rkey)
+		body, err = getRecord(ctx, pdsEndpoint, did, collection, rkey, bearerToken)
 	}
+	if err != nil {
+		return resolveResult{Err: fmt.Errorf("XRPC call failed: %w", err)}
+	}
+
+	return resolveResult{Body: body, CacheHit: cacheHit}
+}
+
+// resolvePDS resolves authority (a handle or DID) down to the PDS endpoint
+// that serves its repo, going through the resolution cache at each step.
+// cacheHit reports whether every step was served from cache.
+func resolvePDS(ctx context.Context, authority string) (did, pdsEndpoint string, cacheHit bool, err error) {
+	cacheHit = true
 
+	did = authority
+	if !strings.HasPrefix(authority, "did:") {
+		resolvedDID, hit, err := resolutionCache.ResolveHandle(ctx, authority, resolveHandle)
+		if err != nil {
+			return "", "", false, fmt.Errorf("failed to resolve handle: %w", err)
+		}
+		cacheHit = cacheHit && hit
+		did = resolvedDID
+	}
+
+	didDoc, hit, err := resolutionCache.ResolveDID(ctx, did, defaultRegistry.Resolve)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("XRPC call failed: %v", err), http.StatusInternalServerError)
-		return
+		return "", "", false, fmt.Errorf("failed to resolve DID: %w", err)
 	}
+	cacheHit = cacheHit && hit
 
-	// Return response as-is
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(response)
+	pdsEndpoint, hit = resolutionCache.PDSEndpoint(did, didDoc)
+	cacheHit = cacheHit && hit
+	if pdsEndpoint == "" {
+		return "", "", false, fmt.Errorf("no PDS endpoint found in DID document")
+	}
+
+	return did, pdsEndpoint, cacheHit, nil
 }
 
 func parseATURI(uri string) (authority, collection, rkey string, err error) {
@@ -113,52 +183,6 @@ func parseATURI(uri string) (authority, collection, rkey string, err error) {
 	return authority, collection, rkey, nil
 }
 
-func resolveHandle(handle string) (string, error) {
-	// Try DNS TXT record first
-	resp, err := http.Get(fmt.Sprintf("https://%s/.well-known/atproto-did", handle))
-	if err == nil && resp.StatusCode == 200 {
-		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
-		if err == nil {
-			return strings.TrimSpace(string(body)), nil
-		}
-	}
-	
-	return "", fmt.Errorf("could not resolve handle")
-}
-
-func resolveDID(did string) (*DIDDocument, error) {
-	var didURL string
-	
-	if strings.HasPrefix(did, "did:plc:") {
-		// Query plc.directory
-		didURL = fmt.Sprintf("https://plc.directory/%s", did)
-	} else if strings.HasPrefix(did, "did:web:") {
-		// Extract domain and construct .well-known URL
-		domain := strings.TrimPrefix(did, "did:web:")
-		didURL = fmt.Sprintf("https://%s/.well-known/did.json", domain)
-	} else {
-		return nil, fmt.Errorf("unsupported DID method")
-	}
-	
-	resp, err := http.Get(didURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("DID resolution failed with status %d", resp.StatusCode)
-	}
-	
-	var didDoc DIDDocument
-	if err := json.NewDecoder(resp.Body).Decode(&didDoc); err != nil {
-		return nil, err
-	}
-	
-	return &didDoc, nil
-}
-
 func extractPDSEndpoint(didDoc *DIDDocument) string {
 	for _, service := range didDoc.Service {
 		if service.Type == "AtprotoPersonalDataServer" || 
@@ -169,47 +193,29 @@ func extractPDSEndpoint(didDoc *DIDDocument) string {
 	return ""
 }
 
-func describeRepo(pdsEndpoint, did string) ([]byte, error) {
-	url := fmt.Sprintf("%s/xrpc/com.atproto.repo.describeRepo?repo=%s", 
-		strings.TrimSuffix(pdsEndpoint, "/"), 
+func describeRepo(ctx context.Context, pdsEndpoint, did, bearerToken string) ([]byte, error) {
+	xrpcURL := fmt.Sprintf("%s/xrpc/com.atproto.repo.describeRepo?repo=%s",
+		strings.TrimSuffix(pdsEndpoint, "/"),
 		url.QueryEscape(did))
-	
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	
-	return io.ReadAll(resp.Body)
+
+	return sharedHTTPClient.GetAuthorized(ctx, xrpcURL, bearerToken)
 }
 
-func listRecords(pdsEndpoint, did, collection string) ([]byte, error) {
-	url := fmt.Sprintf("%s/xrpc/com.atproto.repo.listRecords?repo=%s&collection=%s",
+func listRecords(ctx context.Context, pdsEndpoint, did, collection, bearerToken string) ([]byte, error) {
+	xrpcURL := fmt.Sprintf("%s/xrpc/com.atproto.repo.listRecords?repo=%s&collection=%s",
 		strings.TrimSuffix(pdsEndpoint, "/"),
 		url.QueryEscape(did),
 		url.QueryEscape(collection))
-	
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	
-	return io.ReadAll(resp.Body)
+
+	return sharedHTTPClient.GetAuthorized(ctx, xrpcURL, bearerToken)
 }
 
-func getRecord(pdsEndpoint, did, collection, rkey string) ([]byte, error) {
-	url := fmt.Sprintf("%s/xrpc/com.atproto.repo.getRecord?repo=%s&collection=%s&rkey=%s",
+func getRecord(ctx context.Context, pdsEndpoint, did, collection, rkey, bearerToken string) ([]byte, error) {
+	xrpcURL := fmt.Sprintf("%s/xrpc/com.atproto.repo.getRecord?repo=%s&collection=%s&rkey=%s",
 		strings.TrimSuffix(pdsEndpoint, "/"),
 		url.QueryEscape(did),
 		url.QueryEscape(collection),
 		url.QueryEscape(rkey))
-	
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	
-	return io.ReadAll(resp.Body)
+
+	return sharedHTTPClient.GetAuthorized(ctx, xrpcURL, bearerToken)
 }
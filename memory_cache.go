@@ -0,0 +1,78 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-process, size-bounded CacheBackend with LRU
+// eviction. It's the default backend and requires no external service.
+type MemoryCache struct {
+	mu       sync.Mutex
+	maxLen   int
+	ll       *list.List
+	elements map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// NewMemoryCache returns a MemoryCache holding at most maxLen entries.
+func NewMemoryCache(maxLen int) *MemoryCache {
+	if maxLen <= 0 {
+		maxLen = 10000
+	}
+	return &MemoryCache{
+		maxLen:   maxLen,
+		ll:       list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+func (c *MemoryCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.elements[key]
+	if !ok {
+		return "", false
+	}
+	entry := el.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(el)
+		delete(c.elements, key)
+		return "", false
+	}
+
+	c.ll.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *MemoryCache) Set(key, value string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if el, ok := c.elements[key]; ok {
+		el.Value.(*memoryCacheEntry).value = value
+		el.Value.(*memoryCacheEntry).expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	el := c.ll.PushFront(&memoryCacheEntry{key: key, value: value, expiresAt: expiresAt})
+	c.elements[key] = el
+
+	for c.ll.Len() > c.maxLen {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		delete(c.elements, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheGetSet(t *testing.T) {
+	c := NewMemoryCache(10)
+
+	if _, ok := c.Get("missing"); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+
+	c.Set("a", "1", time.Minute)
+	v, ok := c.Get("a")
+	if !ok || v != "1" {
+		t.Fatalf("Get(a) = %q, %v; want 1, true", v, ok)
+	}
+}
+
+func TestMemoryCacheExpiresEntries(t *testing.T) {
+	c := NewMemoryCache(10)
+
+	c.Set("a", "1", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected expired entry to miss")
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewMemoryCache(2)
+
+	c.Set("a", "1", time.Minute)
+	c.Set("b", "2", time.Minute)
+	c.Get("a") // touch a, making b the least recently used
+	c.Set("c", "3", time.Minute)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be evicted as the least recently used entry")
+	}
+	if v, ok := c.Get("a"); !ok || v != "1" {
+		t.Fatalf("expected a to survive eviction, got %q, %v", v, ok)
+	}
+	if v, ok := c.Get("c"); !ok || v != "3" {
+		t.Fatalf("expected c to be present, got %q, %v", v, ok)
+	}
+}
+
+func TestMemoryCacheSetOverwriteRefreshesRecency(t *testing.T) {
+	c := NewMemoryCache(2)
+
+	c.Set("a", "1", time.Minute)
+	c.Set("b", "2", time.Minute)
+	c.Set("a", "updated", time.Minute) // overwrite a, making b the least recently used
+	c.Set("c", "3", time.Minute)
+
+	if v, ok := c.Get("a"); !ok || v != "updated" {
+		t.Fatalf("Get(a) = %q, %v; want updated, true", v, ok)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be evicted after a's overwrite refreshed its recency")
+	}
+}
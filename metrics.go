@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// cacheMetric tracks hit/miss counts for one cache entry kind.
+type cacheMetric struct {
+	hits   uint64
+	misses uint64
+}
+
+func (m *cacheMetric) hit()  { atomic.AddUint64(&m.hits, 1) }
+func (m *cacheMetric) miss() { atomic.AddUint64(&m.misses, 1) }
+
+var (
+	cacheHandleMetric = &cacheMetric{}
+	cacheDIDMetric    = &cacheMetric{}
+	cachePDSMetric    = &cacheMetric{}
+)
+
+// handleMetrics serves cache hit/miss counters in Prometheus text
+// exposition format.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP lodestone_cache_hits_total Resolution cache hits by entry kind.")
+	fmt.Fprintln(w, "# TYPE lodestone_cache_hits_total counter")
+	for kind, m := range map[string]*cacheMetric{"handle": cacheHandleMetric, "did": cacheDIDMetric, "pds": cachePDSMetric} {
+		fmt.Fprintf(w, "lodestone_cache_hits_total{kind=%q} %d\n", kind, atomic.LoadUint64(&m.hits))
+	}
+
+	fmt.Fprintln(w, "# HELP lodestone_cache_misses_total Resolution cache misses by entry kind.")
+	fmt.Fprintln(w, "# TYPE lodestone_cache_misses_total counter")
+	for kind, m := range map[string]*cacheMetric{"handle": cacheHandleMetric, "did": cacheDIDMetric, "pds": cachePDSMetric} {
+		fmt.Fprintf(w, "lodestone_cache_misses_total{kind=%q} %d\n", kind, atomic.LoadUint64(&m.misses))
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultPLCEndpoint = "https://plc.directory"
+
+// PLCResolver resolves did:plc DIDs against a PLC directory server, falling
+// back to configured mirrors if the primary endpoint fails.
+type PLCResolver struct {
+	endpoint string
+	mirrors  []string
+	timeout  time.Duration
+}
+
+// NewPLCResolver builds a PLCResolver, defaulting to the public
+// plc.directory when cfg.Endpoint is unset.
+func NewPLCResolver(cfg MethodConfig) *PLCResolver {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = defaultPLCEndpoint
+	}
+	return &PLCResolver{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		mirrors:  trimSuffixAll(cfg.Mirrors, "/"),
+		timeout:  cfg.Timeout,
+	}
+}
+
+func (r *PLCResolver) Resolve(ctx context.Context, did string) (*DIDDocument, error) {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	var lastErr error
+	for _, base := range append([]string{r.endpoint}, r.mirrors...) {
+		body, err := sharedHTTPClient.Get(ctx, fmt.Sprintf("%s/%s", base, did))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		var didDoc DIDDocument
+		if err := json.Unmarshal(body, &didDoc); err != nil {
+			lastErr = err
+			continue
+		}
+		return &didDoc, nil
+	}
+	return nil, lastErr
+}
+
+// trimSuffixAll trims suffix from every element of s.
+func trimSuffixAll(s []string, suffix string) []string {
+	out := make([]string, len(s))
+	for i, v := range s {
+		out[i] = strings.TrimSuffix(v, suffix)
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCache is a CacheBackend backed by Redis, letting multiple Lodestone
+// replicas share resolution cache state.
+type RedisCache struct {
+	client *redis.Client
+}
+
+// NewRedisCache connects to the Redis instance at addr.
+func NewRedisCache(addr string) (*RedisCache, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("redis cache backend requires redis_addr")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	return &RedisCache{client: client}, nil
+}
+
+func (c *RedisCache) Get(key string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	value, err := c.client.Get(ctx, key).Result()
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+func (c *RedisCache) Set(key, value string, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	c.client.Set(ctx, key, value, ttl)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DIDResolver resolves DIDs for a single DID method (e.g. "plc", "web", "key").
+type DIDResolver interface {
+	// Resolve resolves did, honoring ctx's deadline for any network call.
+	Resolve(ctx context.Context, did string) (*DIDDocument, error)
+}
+
+// DIDRegistry dispatches DID resolution to the resolver registered for a
+// DID's method, so new methods can be added without touching handleResolve.
+type DIDRegistry struct {
+	mu        sync.RWMutex
+	resolvers map[string]DIDResolver
+}
+
+// NewDIDRegistry returns a registry with no resolvers registered.
+func NewDIDRegistry() *DIDRegistry {
+	return &DIDRegistry{resolvers: make(map[string]DIDResolver)}
+}
+
+// RegisterDIDMethod associates a DIDResolver with a DID method prefix
+// (the segment between "did:" and the method-specific identifier). It
+// overwrites any resolver previously registered for the same method.
+func (reg *DIDRegistry) RegisterDIDMethod(method string, r DIDResolver) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.resolvers[method] = r
+}
+
+// Resolve looks up the resolver for did's method and delegates to it.
+func (reg *DIDRegistry) Resolve(ctx context.Context, did string) (*DIDDocument, error) {
+	parts := strings.SplitN(did, ":", 3)
+	if len(parts) != 3 || parts[0] != "did" {
+		return nil, fmt.Errorf("malformed DID: %s", did)
+	}
+	method := parts[1]
+
+	reg.mu.RLock()
+	r, ok := reg.resolvers[method]
+	reg.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported DID method: %s", method)
+	}
+	return r.Resolve(ctx, did)
+}
+
+// defaultRegistry is the registry wired up at boot and used by handleResolve.
+var defaultRegistry = NewDIDRegistry()
+
+// NewDefaultDIDRegistry builds the registry seeded with Lodestone's built-in
+// resolvers, configured from cfg.
+func NewDefaultDIDRegistry(cfg *Config) *DIDRegistry {
+	reg := NewDIDRegistry()
+	reg.RegisterDIDMethod("plc", NewPLCResolver(cfg.MethodConfig("plc")))
+	reg.RegisterDIDMethod("web", NewWebResolver(cfg.MethodConfig("web")))
+	reg.RegisterDIDMethod("key", NewKeyResolver())
+	return reg
+}
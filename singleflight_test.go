@@ -0,0 +1,95 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleflightGroupCollapsesConcurrentCallers(t *testing.T) {
+	var g singleflightGroup
+	var calls int64
+
+	// start is a barrier so all callers reach g.Do at roughly the same
+	// time regardless of GOMAXPROCS, and fn sleeps briefly once it's
+	// running so the rest of the pack has time to join the same call
+	// before it completes, instead of each one winning its own race and
+	// starting a fresh call. This mirrors how x/sync/singleflight tests
+	// its own collapsing behavior.
+	const n = 20
+	var start sync.WaitGroup
+	start.Add(n)
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start.Done()
+			start.Wait()
+
+			v, err, _ := g.Do("key", func() (interface{}, error) {
+				atomic.AddInt64(&calls, 1)
+				time.Sleep(10 * time.Millisecond)
+				return "value", nil
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results[i] = v
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("expected exactly 1 underlying call, got %d", calls)
+	}
+	for i, v := range results {
+		if v != "value" {
+			t.Fatalf("result %d = %v, want %q", i, v, "value")
+		}
+	}
+}
+
+func TestSingleflightGroupDistinctKeysRunIndependently(t *testing.T) {
+	var g singleflightGroup
+	var calls int64
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		key := string(rune('a' + i))
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			g.Do(key, func() (interface{}, error) {
+				atomic.AddInt64(&calls, 1)
+				return key, nil
+			})
+		}(key)
+	}
+	wg.Wait()
+
+	if calls != 5 {
+		t.Fatalf("expected 5 underlying calls for 5 distinct keys, got %d", calls)
+	}
+}
+
+func TestSingleflightGroupRunsAgainAfterPriorCallCompletes(t *testing.T) {
+	var g singleflightGroup
+	var calls int64
+
+	g.Do("key", func() (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		return nil, nil
+	})
+	g.Do("key", func() (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		return nil, nil
+	})
+
+	if calls != 2 {
+		t.Fatalf("expected 2 calls for sequential (non-overlapping) requests, got %d", calls)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WebResolver resolves did:web DIDs via the .well-known/did.json convention.
+type WebResolver struct {
+	// overrideHost, when set, is used instead of the domain encoded in the
+	// DID itself (useful for pointing a single configured mirror at a
+	// specific did:web host in tests or restricted environments).
+	overrideHost string
+	// mirrors are additional hosts tried, in order, if the primary host's
+	// well-known document can't be fetched.
+	mirrors []string
+	timeout time.Duration
+}
+
+// NewWebResolver builds a WebResolver. cfg.Endpoint, if set, overrides the
+// domain that every did:web lookup is sent to; otherwise the domain is
+// derived from each DID. cfg.Mirrors are tried in order after the primary
+// host fails, and cfg.Timeout bounds each lookup.
+func NewWebResolver(cfg MethodConfig) *WebResolver {
+	return &WebResolver{
+		overrideHost: cfg.Endpoint,
+		mirrors:      cfg.Mirrors,
+		timeout:      cfg.Timeout,
+	}
+}
+
+func (r *WebResolver) Resolve(ctx context.Context, did string) (*DIDDocument, error) {
+	domain := strings.TrimPrefix(did, "did:web:")
+	host := domain
+	if r.overrideHost != "" {
+		host = r.overrideHost
+	}
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	var lastErr error
+	for _, h := range append([]string{host}, r.mirrors...) {
+		body, err := sharedHTTPClient.Get(ctx, fmt.Sprintf("https://%s/.well-known/did.json", h))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		var didDoc DIDDocument
+		if err := json.Unmarshal(body, &didDoc); err != nil {
+			lastErr = err
+			continue
+		}
+		return &didDoc, nil
+	}
+	return nil, lastErr
+}